@@ -0,0 +1,117 @@
+package watson
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// ErrIncompleteValue is returned when a record ends without leaving exactly
+// one Value on the VM's stack, e.g. a key and value pushed after Onew with no
+// Oadd to add them.
+var ErrIncompleteValue = errors.New("watson: incomplete value")
+
+// Decoder reads a single Watson Representation value from an input stream.
+type Decoder struct {
+	l  *lexer.Lexer
+	vm *vm.VM
+}
+
+// NewDecoder returns a new Decoder that reads from r.
+// An optional vm.VMConfig bounds the resources spent decoding untrusted input.
+func NewDecoder(r io.Reader, config ...vm.VMConfig) *Decoder {
+	return &Decoder{l: lexer.NewLexer(r), vm: vm.NewVM(config...)}
+}
+
+// Decode runs the VM on ops read from the underlying reader until it hits
+// EOF, then requires the stack to hold exactly one Value, and decodes that
+// Value into out via types.FromValue.
+func (d *Decoder) Decode(out interface{}) error {
+	for {
+		op, err := d.l.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := d.vm.Feed(op); err != nil {
+			return fmt.Errorf("watson: %w", err)
+		}
+	}
+	if d.vm.Depth() != 1 {
+		return ErrIncompleteValue
+	}
+	val, err := d.vm.Pop()
+	if err != nil {
+		return err
+	}
+	return types.FromValue(val, out)
+}
+
+// StreamDecoder reads successive top-level Values out of a single stream
+// produced by repeated calls to Encoder.Encode, one per call to Decode.
+//
+// Watson's op set has no explicit terminator for a Value under construction
+// (e.g. nothing marks "no more Oadd is coming"), so top-level Values can't be
+// told apart by watching the VM's stack depth alone. Instead, StreamDecoder
+// relies on the record separator that Encoder writes after every Value: it
+// reads one newline-delimited record at a time and runs a fresh Lexer over
+// each one, feeding its ops into a single VM that all records share.
+type StreamDecoder struct {
+	r  *bufio.Reader
+	vm *vm.VM
+}
+
+// NewStreamDecoder returns a new StreamDecoder that reads from r.
+// An optional vm.VMConfig bounds the resources spent decoding untrusted input.
+func NewStreamDecoder(r io.Reader, config ...vm.VMConfig) *StreamDecoder {
+	return &StreamDecoder{r: bufio.NewReader(r), vm: vm.NewVM(config...)}
+}
+
+// Decode decodes the next top-level Value in the stream into out.
+// It returns io.EOF once there are no more Values left to read.
+func (s *StreamDecoder) Decode(out interface{}) error {
+	val, err := s.Next()
+	if err != nil {
+		return err
+	}
+	return types.FromValue(val, out)
+}
+
+// Next returns the next top-level Value in the stream.
+// It returns io.EOF once there are no more Values left to read.
+func (s *StreamDecoder) Next() (*types.Value, error) {
+	record, err := s.r.ReadBytes(recordSeparator)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(record) == 0 {
+		return nil, io.EOF
+	}
+	record = bytes.TrimSuffix(record, []byte{recordSeparator})
+
+	l := lexer.NewLexer(bytes.NewReader(record))
+	for {
+		op, opErr := l.Next()
+		if opErr == io.EOF {
+			break
+		}
+		if opErr != nil {
+			return nil, opErr
+		}
+		if feedErr := s.vm.Feed(op); feedErr != nil {
+			return nil, fmt.Errorf("watson: %w", feedErr)
+		}
+	}
+	if s.vm.Depth() != 1 {
+		return nil, ErrIncompleteValue
+	}
+	return s.vm.Pop()
+}
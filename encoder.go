@@ -0,0 +1,168 @@
+// Package watson provides a streaming, encoding/gob-like API on top of
+// pkg/types, pkg/vm, and pkg/lexer.
+package watson
+
+import (
+	"io"
+	"math"
+	"sort"
+
+	"github.com/genkami/watson/pkg/lexer"
+	"github.com/genkami/watson/pkg/types"
+	"github.com/genkami/watson/pkg/vm"
+)
+
+// recordSeparator is written after every encoded Value so that StreamDecoder
+// can tell where one top-level Value ends and the next begins without having
+// to track container nesting. It is never an opcode in either lexer table,
+// so a Lexer silently skips over it.
+const recordSeparator = '\n'
+
+// Encoder writes Watson Representation to an output stream.
+type Encoder struct {
+	w io.Writer
+	u *lexer.Unlexer
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, u: lexer.NewUnlexer(w)}
+}
+
+// Encode converts v into a *types.Value via types.ToValue, then writes the
+// minimal sequence of vm.Ops that reconstructs that Value when fed into a vm.VM,
+// followed by a record separator.
+//
+// Each record is written starting from lexer.A, the same Mode a freshly
+// constructed Lexer starts from, so that StreamDecoder's per-record Lexer
+// can always read a record back regardless of which Mode a previous record
+// happened to leave the Unlexer in.
+func (e *Encoder) Encode(v interface{}) error {
+	e.u.Mode = lexer.A
+	val := types.ToValue(v)
+	for _, op := range encodeValue(val) {
+		if err := e.u.WriteOp(op); err != nil {
+			return err
+		}
+	}
+	_, err := e.w.Write([]byte{recordSeparator})
+	return err
+}
+
+func encodeValue(v *types.Value) []vm.Op {
+	switch v.Kind {
+	case types.Nil:
+		return []vm.Op{vm.Nnew}
+	case types.Bool:
+		return encodeBool(v.Bool)
+	case types.Int:
+		return encodeInt(v.Int)
+	case types.Uint:
+		ops := encodeInt(int64(v.Uint))
+		return append(ops, vm.Itou)
+	case types.Float:
+		return encodeFloat(v.Float)
+	case types.String:
+		return encodeString(v.String)
+	case types.Array:
+		return encodeArray(v.Array)
+	case types.Object:
+		return encodeObject(v.Object)
+	default:
+		panic("watson: encodeValue: unknown Kind")
+	}
+}
+
+func encodeBool(b bool) []vm.Op {
+	ops := []vm.Op{vm.Bnew}
+	if b {
+		ops = append(ops, vm.Bneg)
+	}
+	return ops
+}
+
+// encodeInt emits the op sequence that builds n on the stack using the
+// standard double-and-add method: start from 0 and, for every bit of
+// |n| from the most significant down to the least significant, shift left
+// and optionally increment, negating at the end if n is negative.
+func encodeInt(n int64) []vm.Op {
+	if n == 0 {
+		return []vm.Op{vm.Inew}
+	}
+	neg := n < 0
+	m := uint64(n)
+	if neg {
+		m = uint64(-n)
+	}
+	ops := []vm.Op{vm.Inew}
+	started := false
+	for bit := 63; bit >= 0; bit-- {
+		set := (m>>uint(bit))&1 == 1
+		if !started {
+			if !set {
+				continue
+			}
+			ops = append(ops, vm.Iinc)
+			started = true
+			continue
+		}
+		ops = append(ops, vm.Ishl)
+		if set {
+			ops = append(ops, vm.Iinc)
+		}
+	}
+	if neg {
+		ops = append(ops, vm.Ineg)
+	}
+	return ops
+}
+
+func encodeFloat(f float64) []vm.Op {
+	switch {
+	case math.IsInf(f, 1):
+		return []vm.Op{vm.Finf}
+	case math.IsInf(f, -1):
+		return []vm.Op{vm.Finf, vm.Fneg}
+	case math.IsNaN(f):
+		return []vm.Op{vm.Fnan}
+	default:
+		ops := encodeInt(int64(math.Float64bits(f)))
+		return append(ops, vm.Itof)
+	}
+}
+
+func encodeString(s []byte) []vm.Op {
+	ops := []vm.Op{vm.Snew}
+	for _, b := range s {
+		ops = append(ops, encodeInt(int64(b))...)
+		ops = append(ops, vm.Sadd)
+	}
+	return ops
+}
+
+func encodeArray(a []*types.Value) []vm.Op {
+	ops := []vm.Op{vm.Anew}
+	for _, elem := range a {
+		ops = append(ops, encodeValue(elem)...)
+		ops = append(ops, vm.Aadd)
+	}
+	return ops
+}
+
+// encodeObject emits keys in sorted order so that encoding the same
+// map[string]*types.Value always produces the same byte sequence.
+func encodeObject(o map[string]*types.Value) []vm.Op {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	ops := []vm.Op{vm.Onew}
+	for _, k := range keys {
+		ops = append(ops, encodeString([]byte(k))...)
+		ops = append(ops, encodeValue(o[k])...)
+		ops = append(ops, vm.Oadd)
+	}
+	return ops
+}
@@ -0,0 +1,214 @@
+// Package msgpack converts between types.Value and MessagePack, using
+// tinylib/msgp's low-level Reader/Writer since both formats share the same
+// int/uint/float/str/bin/array/map/bool/nil type model.
+package msgpack
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/tinylib/msgp/msgp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+// ErrUnsupportedExt is returned by FromMsgpack when the input contains a
+// MessagePack ext type, which has no corresponding types.Kind.
+var ErrUnsupportedExt = errors.New("msgpack: unsupported ext type")
+
+// ToMsgpack encodes v as MessagePack.
+//
+// types.String is written as fixstr/str8/str16/str32 when it holds valid UTF-8,
+// and as bin8/bin16/bin32 otherwise, since MessagePack strings must be UTF-8.
+// types.Float is always written as a 64-bit float so that its bit pattern,
+// including NaN and ±Inf, survives a round trip through FromMsgpack.
+// types.Uint is always written using one of MessagePack's dedicated
+// uint8/16/32/64 markers, never the positive-fixint range that Int also
+// uses, so that FromMsgpack can tell Int and Uint apart again.
+func ToMsgpack(v *types.Value) ([]byte, error) {
+	var buf bytes.Buffer
+	w := msgp.NewWriter(&buf)
+	if err := writeValue(w, v); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeValue(w *msgp.Writer, v *types.Value) error {
+	switch v.Kind {
+	case types.Nil:
+		return w.WriteNil()
+	case types.Bool:
+		return w.WriteBool(v.Bool)
+	case types.Int:
+		return w.WriteInt64(v.Int)
+	case types.Uint:
+		return writeUint(w, v.Uint)
+	case types.Float:
+		return w.WriteFloat64(v.Float)
+	case types.String:
+		if utf8.Valid(v.String) {
+			return w.WriteStringFromBytes(v.String)
+		}
+		return w.WriteBytes(v.String)
+	case types.Array:
+		if err := w.WriteArrayHeader(uint32(len(v.Array))); err != nil {
+			return err
+		}
+		for _, elem := range v.Array {
+			if err := writeValue(w, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	case types.Object:
+		if err := w.WriteMapHeader(uint32(len(v.Object))); err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(v.Object))
+		for k := range v.Object {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if err := w.WriteString(k); err != nil {
+				return err
+			}
+			if err := writeValue(w, v.Object[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("msgpack: unknown Kind: %v", v.Kind)
+	}
+}
+
+// writeUint writes u with one of MessagePack's uint8/16/32/64 markers
+// (0xcc-0xcf), chosen by magnitude, writing the bytes itself. msgp.Writer's
+// WriteUint8/16/32/64 would otherwise pick whichever encoding is most
+// compact, which for u <= 127 is the same positive-fixint byte that
+// WriteInt64 uses for small Ints, making Uint and Int indistinguishable
+// again once decoded.
+func writeUint(w *msgp.Writer, u uint64) error {
+	var b []byte
+	switch {
+	case u <= math.MaxUint8:
+		b = []byte{0xcc, byte(u)}
+	case u <= math.MaxUint16:
+		b = []byte{0xcd, byte(u >> 8), byte(u)}
+	case u <= math.MaxUint32:
+		b = []byte{0xce, byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}
+	default:
+		b = []byte{0xcf, byte(u >> 56), byte(u >> 48), byte(u >> 40), byte(u >> 32),
+			byte(u >> 24), byte(u >> 16), byte(u >> 8), byte(u)}
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// FromMsgpack decodes MessagePack-encoded b into a *types.Value.
+// It returns ErrUnsupportedExt if b contains an ext type.
+func FromMsgpack(b []byte) (*types.Value, error) {
+	r := msgp.NewReader(bytes.NewReader(b))
+	return readValue(r)
+}
+
+func readValue(r *msgp.Reader) (*types.Value, error) {
+	t, err := r.NextType()
+	if err != nil {
+		return nil, err
+	}
+	switch t {
+	case msgp.NilType:
+		if err := r.ReadNil(); err != nil {
+			return nil, err
+		}
+		return types.NewNilValue(), nil
+	case msgp.BoolType:
+		b, err := r.ReadBool()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewBoolValue(b), nil
+	case msgp.IntType:
+		n, err := r.ReadInt64()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewIntValue(n), nil
+	case msgp.UintType:
+		n, err := r.ReadUint64()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewUintValue(n), nil
+	case msgp.Float64Type:
+		f, err := r.ReadFloat64()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewFloatValue(f), nil
+	case msgp.Float32Type:
+		f, err := r.ReadFloat32()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewFloatValue(float64(f)), nil
+	case msgp.StrType:
+		s, err := r.ReadString()
+		if err != nil {
+			return nil, err
+		}
+		return types.NewStringValue([]byte(s)), nil
+	case msgp.BinType:
+		bin, err := r.ReadBytes(nil)
+		if err != nil {
+			return nil, err
+		}
+		return types.NewStringValue(bin), nil
+	case msgp.ArrayType:
+		size, err := r.ReadArrayHeader()
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]*types.Value, 0, size)
+		for i := uint32(0); i < size; i++ {
+			elem, err := readValue(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, elem)
+		}
+		return types.NewArrayValue(arr), nil
+	case msgp.MapType:
+		size, err := r.ReadMapHeader()
+		if err != nil {
+			return nil, err
+		}
+		obj := make(map[string]*types.Value, size)
+		for i := uint32(0); i < size; i++ {
+			key, err := r.ReadString()
+			if err != nil {
+				return nil, err
+			}
+			val, err := readValue(r)
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = val
+		}
+		return types.NewObjectValue(obj), nil
+	case msgp.ExtensionType:
+		return nil, ErrUnsupportedExt
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type: %v", t)
+	}
+}
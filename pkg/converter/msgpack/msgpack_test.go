@@ -0,0 +1,116 @@
+package msgpack
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func roundTrip(t *testing.T, v *types.Value) *types.Value {
+	t.Helper()
+	b, err := ToMsgpack(v)
+	if err != nil {
+		t.Fatalf("ToMsgpack failed: %s", err)
+	}
+	got, err := FromMsgpack(b)
+	if err != nil {
+		t.Fatalf("FromMsgpack failed: %s", err)
+	}
+	return got
+}
+
+func TestRoundTripInt(t *testing.T) {
+	want := types.NewIntValue(-123)
+	got := roundTrip(t, want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripUint(t *testing.T) {
+	want := types.NewUintValue(123)
+	got := roundTrip(t, want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestRoundTripSmallUintIsNotConfusedWithInt guards against Uint values in
+// the positive-fixint range (0-127) losing their Kind: a naive encoding
+// that lets msgp pick the most compact marker writes the same byte for
+// NewUintValue(5) as it would for NewIntValue(5), so FromMsgpack would
+// decode it back as an Int instead.
+func TestRoundTripSmallUintIsNotConfusedWithInt(t *testing.T) {
+	want := types.NewUintValue(5)
+	got := roundTrip(t, want)
+	if got.Kind != types.Uint {
+		t.Fatalf("expected Kind Uint, got %v", got.Kind)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripString(t *testing.T) {
+	want := types.NewStringValue([]byte("hey"))
+	got := roundTrip(t, want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripNonUTF8StringAsBin(t *testing.T) {
+	want := types.NewStringValue([]byte{0xff, 0xfe, 0x00})
+	got := roundTrip(t, want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripArray(t *testing.T) {
+	want := types.NewArrayValue([]*types.Value{types.NewIntValue(1), types.NewStringValue([]byte("x"))})
+	got := roundTrip(t, want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripObject(t *testing.T) {
+	want := types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewIntValue(1),
+		"b": types.NewBoolValue(true),
+	})
+	got := roundTrip(t, want)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTripNaNPreservesBitPattern(t *testing.T) {
+	nan := math.Float64frombits(0x7ff8000000000001)
+	want := types.NewFloatValue(nan)
+	got := roundTrip(t, want)
+	if math.Float64bits(got.Float) != math.Float64bits(nan) {
+		t.Errorf("expected bit pattern %x, got %x", math.Float64bits(nan), math.Float64bits(got.Float))
+	}
+}
+
+func TestRoundTripInf(t *testing.T) {
+	want := types.NewFloatValue(math.Inf(-1))
+	got := roundTrip(t, want)
+	if got.Float != math.Inf(-1) {
+		t.Errorf("expected -Inf, got %v", got.Float)
+	}
+}
+
+func TestFromMsgpackReturnsErrUnsupportedExtForExtTypes(t *testing.T) {
+	// fixext1: type 0x01, 1 data byte.
+	b := []byte{0xd4, 0x01, 0x00}
+	_, err := FromMsgpack(b)
+	if err != ErrUnsupportedExt {
+		t.Errorf("expected ErrUnsupportedExt, got %v", err)
+	}
+}
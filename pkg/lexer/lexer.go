@@ -17,6 +17,14 @@ const (
 	S
 )
 
+// Toggle returns the opposite Mode of m.
+func (m Mode) Toggle() Mode {
+	if m == A {
+		return S
+	}
+	return A
+}
+
 var opTableA = map[byte]vm.Op{
 	char("B"): vm.Inew,
 	char("u"): vm.Iinc,
@@ -25,6 +33,7 @@ var opTableA = map[byte]vm.Op{
 	char("A"): vm.Ineg,
 	char("e"): vm.Isht,
 	char("i"): vm.Itof,
+	char("I"): vm.Itou,
 	char("q"): vm.Finf,
 	char("t"): vm.Fnan,
 	char("p"): vm.Fneg,
@@ -52,6 +61,7 @@ var opTableS = map[byte]vm.Op{
 	char("r"): vm.Ineg,
 	char("A"): vm.Isht,
 	char("z"): vm.Itof,
+	char("U"): vm.Itou,
 	char("p"): vm.Finf,
 	char("b"): vm.Fnan,
 	char("u"): vm.Fneg,
@@ -82,13 +92,27 @@ func init() {
 	}
 }
 
+func opTable(m Mode) map[byte]vm.Op {
+	if m == S {
+		return opTableS
+	}
+	return opTableA
+}
+
+func reversedTable(m Mode) map[vm.Op]byte {
+	if m == S {
+		return reversedTableS
+	}
+	return reversedTableA
+}
+
 func readOp(m Mode, b byte) (op vm.Op, ok bool) {
-	op, ok = opTableA[b]
+	op, ok = opTable(m)[b]
 	return
 }
 
 func showOp(m Mode, op vm.Op) byte {
-	if b, ok := reversedTableA[op]; ok {
+	if b, ok := reversedTable(m)[op]; ok {
 		return b
 	}
 	panic(fmt.Errorf("unknown Op: %#v\n", op))
@@ -100,17 +124,21 @@ func char(s string) byte {
 
 // Lexer is responsible for converting a Watson Representation into a sequence of vm.Ops.
 type Lexer struct {
-	r   io.Reader
-	buf [1]byte
+	r    io.Reader
+	buf  [1]byte
+	Mode Mode
 }
 
 // Creates a new Lexer that reads Watson Representation from r.
 func NewLexer(r io.Reader) *Lexer {
-	return &Lexer{r: r}
+	return &Lexer{r: r, Mode: A}
 }
 
 // Returns the next Op.
 // This returns io.EOF if it hits on the end of the input.
+//
+// Next keeps track of the current Mode and switches between opTableA and opTableS
+// whenever it emits an Onew, because Onew is the op that toggles the mode in the Watson spec.
 func (l *Lexer) Next() (vm.Op, error) {
 	for {
 		_, err := l.r.Read(l.buf[:])
@@ -118,8 +146,38 @@ func (l *Lexer) Next() (vm.Op, error) {
 			// Note that it returns io.EOF if the underlying Reader returns io.EOF.
 			return 0, err
 		}
-		if op, ok := readOp(A, l.buf[0]); ok {
+		if op, ok := readOp(l.Mode, l.buf[0]); ok {
+			if op == vm.Onew {
+				l.Mode = l.Mode.Toggle()
+			}
 			return op, nil
 		}
 	}
 }
+
+// Unlexer is responsible for converting a sequence of vm.Ops into a Watson Representation.
+// It is the counterpart of Lexer and tracks the same Mode so that the bytes it writes
+// can be read back by a Lexer unambiguously.
+type Unlexer struct {
+	w    io.Writer
+	buf  [1]byte
+	Mode Mode
+}
+
+// Creates a new Unlexer that writes Watson Representation to w.
+func NewUnlexer(w io.Writer) *Unlexer {
+	return &Unlexer{w: w, Mode: A}
+}
+
+// WriteOp writes the byte representation of op, toggling Mode whenever op is Onew,
+// just like Lexer.Next does when reading it back.
+func (u *Unlexer) WriteOp(op vm.Op) error {
+	u.buf[0] = showOp(u.Mode, op)
+	if _, err := u.w.Write(u.buf[:]); err != nil {
+		return err
+	}
+	if op == vm.Onew {
+		u.Mode = u.Mode.Toggle()
+	}
+	return nil
+}
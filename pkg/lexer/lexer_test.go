@@ -0,0 +1,95 @@
+package lexer
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/genkami/watson/pkg/vm"
+)
+
+func TestLexerTogglesModeOnNestedObject(t *testing.T) {
+	// "~" is Onew in table A, entering table S.
+	// "+" is Onew in table S, entering table A again.
+	// "M" is Oadd in table A.
+	l := NewLexer(bytes.NewReader([]byte("~+M")))
+
+	op, err := l.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if op != vm.Onew {
+		t.Errorf("expected Onew, got %v", op)
+	}
+	if l.Mode != S {
+		t.Errorf("expected mode S after outer Onew, got %v", l.Mode)
+	}
+
+	op, err = l.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if op != vm.Onew {
+		t.Errorf("expected Onew, got %v", op)
+	}
+	if l.Mode != A {
+		t.Errorf("expected mode A after nested Onew, got %v", l.Mode)
+	}
+
+	op, err = l.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if op != vm.Oadd {
+		t.Errorf("expected Oadd, got %v", op)
+	}
+
+	_, err = l.Next()
+	if err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestItouRoundTripsInBothModes(t *testing.T) {
+	for _, mode := range []Mode{A, S} {
+		var buf bytes.Buffer
+		u := NewUnlexer(&buf)
+		u.Mode = mode
+		if err := u.WriteOp(vm.Itou); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		l := NewLexer(bytes.NewReader(buf.Bytes()))
+		l.Mode = mode
+		op, err := l.Next()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if op != vm.Itou {
+			t.Errorf("mode %v: expected Itou, got %v", mode, op)
+		}
+	}
+}
+
+func TestUnlexerTogglesModeOnOnewAndRoundTrips(t *testing.T) {
+	ops := []vm.Op{vm.Onew, vm.Onew, vm.Oadd}
+
+	var buf bytes.Buffer
+	u := NewUnlexer(&buf)
+	for _, op := range ops {
+		if err := u.WriteOp(op); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	l := NewLexer(bytes.NewReader(buf.Bytes()))
+	for i, want := range ops {
+		got, err := l.Next()
+		if err != nil {
+			t.Fatalf("unexpected error at op %d: %s", i, err)
+		}
+		if got != want {
+			t.Errorf("op %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
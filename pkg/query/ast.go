@@ -0,0 +1,82 @@
+package query
+
+// expr is the common interface implemented by every node in a compiled
+// query's AST.
+type expr interface {
+	isExpr()
+}
+
+// pathExpr is a chain of steps applied one after another, starting from
+// either the value passed to Program.Run (at the top level) or the current
+// candidate inside a predicate (when the chain starts with "@").
+type pathExpr struct {
+	steps []step
+}
+
+func (*pathExpr) isExpr() {}
+
+// step is one segment of a pathExpr.
+type step interface {
+	isStep()
+}
+
+// fieldStep accesses a field of an Object, either via ".name" or via a
+// bracketed string literal such as `["name"]`.
+type fieldStep struct {
+	name string
+}
+
+func (*fieldStep) isStep() {}
+
+// indexStep accesses an element of an Array by position, e.g. `[0]`.
+type indexStep struct {
+	index int
+}
+
+func (*indexStep) isStep() {}
+
+// wildcardStep expands to every element of an Array or every value of an
+// Object, e.g. `[*]`.
+type wildcardStep struct{}
+
+func (*wildcardStep) isStep() {}
+
+// filterStep keeps only the elements of an Array or the values of an Object
+// for which cond evaluates to a truthy Bool, e.g. `[?(@.qty > 3)]`.
+type filterStep struct {
+	cond expr
+}
+
+func (*filterStep) isStep() {}
+
+// literalExpr is a literal number, string, bool, or null.
+type literalExpr struct {
+	value *literal
+}
+
+func (*literalExpr) isExpr() {}
+
+// binaryExpr applies a binary operator to two sub-expressions. It covers
+// both arithmetic (+ - * /) and comparisons (== != < <= > >= && ||).
+type binaryExpr struct {
+	op   string
+	l, r expr
+}
+
+func (*binaryExpr) isExpr() {}
+
+// unaryExpr applies a unary operator, currently only numeric negation.
+type unaryExpr struct {
+	op string
+	e  expr
+}
+
+func (*unaryExpr) isExpr() {}
+
+// callExpr calls one of query's built-in functions: len, keys, or type.
+type callExpr struct {
+	name string
+	args []expr
+}
+
+func (*callExpr) isExpr() {}
@@ -0,0 +1,426 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func eval(e expr, cur *types.Value) ([]*types.Value, error) {
+	switch e := e.(type) {
+	case *pathExpr:
+		return evalPath(e, cur)
+	case *literalExpr:
+		return []*types.Value{literalToValue(e.value)}, nil
+	case *binaryExpr:
+		return evalBinary(e, cur)
+	case *unaryExpr:
+		return evalUnary(e, cur)
+	case *callExpr:
+		return evalCall(e, cur)
+	default:
+		return nil, fmt.Errorf("query: unknown expr %T", e)
+	}
+}
+
+// evalScalar evaluates e and requires it to produce exactly one Value.
+func evalScalar(e expr, cur *types.Value) (*types.Value, error) {
+	vs, err := eval(e, cur)
+	if err != nil {
+		return nil, err
+	}
+	if len(vs) != 1 {
+		return nil, fmt.Errorf("query: expected a single value, got %d", len(vs))
+	}
+	return vs[0], nil
+}
+
+func evalPath(p *pathExpr, cur *types.Value) ([]*types.Value, error) {
+	vals := []*types.Value{cur}
+	for _, s := range p.steps {
+		var next []*types.Value
+		switch s := s.(type) {
+		case *fieldStep:
+			for _, v := range vals {
+				if v.Kind != types.Object {
+					continue
+				}
+				if fv, ok := v.Object[s.name]; ok {
+					next = append(next, fv)
+				}
+			}
+		case *indexStep:
+			for _, v := range vals {
+				if v.Kind != types.Array {
+					continue
+				}
+				idx := s.index
+				if idx < 0 {
+					idx += len(v.Array)
+				}
+				if idx >= 0 && idx < len(v.Array) {
+					next = append(next, v.Array[idx])
+				}
+			}
+		case *wildcardStep:
+			for _, v := range vals {
+				switch v.Kind {
+				case types.Array:
+					next = append(next, v.Array...)
+				case types.Object:
+					for _, k := range sortedKeys(v.Object) {
+						next = append(next, v.Object[k])
+					}
+				}
+			}
+		case *filterStep:
+			for _, v := range vals {
+				var candidates []*types.Value
+				switch v.Kind {
+				case types.Array:
+					candidates = v.Array
+				case types.Object:
+					for _, k := range sortedKeys(v.Object) {
+						candidates = append(candidates, v.Object[k])
+					}
+				default:
+					continue
+				}
+				for _, c := range candidates {
+					ok, err := evalBool(s.cond, c)
+					if err != nil {
+						// A predicate that fails to evaluate on a given
+						// candidate (e.g. a missing field) simply excludes
+						// that candidate, as in JSONPath.
+						continue
+					}
+					if ok {
+						next = append(next, c)
+					}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("query: unknown step %T", s)
+		}
+		vals = next
+	}
+	return vals, nil
+}
+
+func sortedKeys(m map[string]*types.Value) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func evalBool(e expr, cur *types.Value) (bool, error) {
+	v, err := evalScalar(e, cur)
+	if err != nil {
+		return false, err
+	}
+	if v.Kind != types.Bool {
+		return false, fmt.Errorf("query: expected bool, got %v", v.Kind)
+	}
+	return v.Bool, nil
+}
+
+func literalToValue(l *literal) *types.Value {
+	switch l.kind {
+	case literalNumber:
+		return types.NewFloatValue(l.num)
+	case literalString:
+		return types.NewStringValue([]byte(l.str))
+	case literalBool:
+		return types.NewBoolValue(l.b)
+	default:
+		return types.NewNilValue()
+	}
+}
+
+func isNumericKind(k types.Kind) bool {
+	return k == types.Int || k == types.Uint || k == types.Float
+}
+
+// toFloat64 promotes any of Watson's three numeric Kinds to float64. This is
+// the documented promotion rule query uses for both comparisons and mixed
+// Int/Uint/Float arithmetic.
+func toFloat64(v *types.Value) float64 {
+	switch v.Kind {
+	case types.Int:
+		return float64(v.Int)
+	case types.Uint:
+		return float64(v.Uint)
+	default:
+		return v.Float
+	}
+}
+
+func evalBinary(b *binaryExpr, cur *types.Value) ([]*types.Value, error) {
+	switch b.op {
+	case "&&":
+		l, err := evalBool(b.l, cur)
+		if err != nil {
+			return nil, err
+		}
+		if !l {
+			return []*types.Value{types.NewBoolValue(false)}, nil
+		}
+		r, err := evalBool(b.r, cur)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.Value{types.NewBoolValue(r)}, nil
+	case "||":
+		l, err := evalBool(b.l, cur)
+		if err != nil {
+			return nil, err
+		}
+		if l {
+			return []*types.Value{types.NewBoolValue(true)}, nil
+		}
+		r, err := evalBool(b.r, cur)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.Value{types.NewBoolValue(r)}, nil
+	case "==", "!=", "<", "<=", ">", ">=":
+		lv, err := evalScalar(b.l, cur)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := evalScalar(b.r, cur)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := compare(b.op, lv, rv)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.Value{types.NewBoolValue(ok)}, nil
+	case "+", "-", "*", "/":
+		lv, err := evalScalar(b.l, cur)
+		if err != nil {
+			return nil, err
+		}
+		rv, err := evalScalar(b.r, cur)
+		if err != nil {
+			return nil, err
+		}
+		v, err := arithmetic(b.op, lv, rv)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.Value{v}, nil
+	default:
+		return nil, fmt.Errorf("query: unknown operator %q", b.op)
+	}
+}
+
+// compare respects Watson's separate Int/Uint/Float Kinds by promoting all
+// three to float64 before ordering or testing equality, and compares String
+// operands as raw byte slices rather than decoded text.
+func compare(op string, a, b *types.Value) (bool, error) {
+	switch {
+	case isNumericKind(a.Kind) && isNumericKind(b.Kind):
+		af, bf := toFloat64(a), toFloat64(b)
+		return compareOrdered(op, af < bf, af == bf)
+	case a.Kind == types.String && b.Kind == types.String:
+		c := bytes.Compare(a.String, b.String)
+		return compareOrdered(op, c < 0, c == 0)
+	case a.Kind == types.Bool && b.Kind == types.Bool:
+		switch op {
+		case "==":
+			return a.Bool == b.Bool, nil
+		case "!=":
+			return a.Bool != b.Bool, nil
+		}
+		return false, fmt.Errorf("query: can't order bool values")
+	case a.Kind == types.Nil || b.Kind == types.Nil:
+		eq := a.Kind == types.Nil && b.Kind == types.Nil
+		switch op {
+		case "==":
+			return eq, nil
+		case "!=":
+			return !eq, nil
+		}
+		return false, fmt.Errorf("query: can't order nil")
+	default:
+		return false, fmt.Errorf("query: can't compare %v and %v", a.Kind, b.Kind)
+	}
+}
+
+func compareOrdered(op string, less, equal bool) (bool, error) {
+	switch op {
+	case "==":
+		return equal, nil
+	case "!=":
+		return !equal, nil
+	case "<":
+		return less, nil
+	case "<=":
+		return less || equal, nil
+	case ">":
+		return !less && !equal, nil
+	case ">=":
+		return !less, nil
+	default:
+		return false, fmt.Errorf("query: unknown comparison operator %q", op)
+	}
+}
+
+var errDivByZero = fmt.Errorf("query: division by zero")
+
+// arithmetic keeps Int+Int as Int and Uint+Uint as Uint, and otherwise
+// promotes both operands to float64, matching the same numeric promotion
+// rule compare uses.
+func arithmetic(op string, a, b *types.Value) (*types.Value, error) {
+	if !isNumericKind(a.Kind) || !isNumericKind(b.Kind) {
+		return nil, fmt.Errorf("query: arithmetic requires numeric operands, got %v and %v", a.Kind, b.Kind)
+	}
+	if a.Kind == types.Int && b.Kind == types.Int {
+		if op == "/" && b.Int == 0 {
+			return nil, errDivByZero
+		}
+		return types.NewIntValue(intArith(op, a.Int, b.Int)), nil
+	}
+	if a.Kind == types.Uint && b.Kind == types.Uint {
+		if op == "/" && b.Uint == 0 {
+			return nil, errDivByZero
+		}
+		return types.NewUintValue(uintArith(op, a.Uint, b.Uint)), nil
+	}
+	af, bf := toFloat64(a), toFloat64(b)
+	return types.NewFloatValue(floatArith(op, af, bf)), nil
+}
+
+func intArith(op string, a, b int64) int64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	default:
+		return a / b
+	}
+}
+
+func uintArith(op string, a, b uint64) uint64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	default:
+		return a / b
+	}
+}
+
+func floatArith(op string, a, b float64) float64 {
+	switch op {
+	case "+":
+		return a + b
+	case "-":
+		return a - b
+	case "*":
+		return a * b
+	default:
+		return a / b
+	}
+}
+
+func evalUnary(u *unaryExpr, cur *types.Value) ([]*types.Value, error) {
+	v, err := evalScalar(u.e, cur)
+	if err != nil {
+		return nil, err
+	}
+	switch v.Kind {
+	case types.Int:
+		return []*types.Value{types.NewIntValue(-v.Int)}, nil
+	case types.Uint:
+		return []*types.Value{types.NewIntValue(-int64(v.Uint))}, nil
+	case types.Float:
+		return []*types.Value{types.NewFloatValue(-v.Float)}, nil
+	default:
+		return nil, fmt.Errorf("query: can't negate %v", v.Kind)
+	}
+}
+
+func evalCall(c *callExpr, cur *types.Value) ([]*types.Value, error) {
+	switch c.name {
+	case "len":
+		if len(c.args) != 1 {
+			return nil, fmt.Errorf("query: len takes exactly one argument")
+		}
+		v, err := evalScalar(c.args[0], cur)
+		if err != nil {
+			return nil, err
+		}
+		switch v.Kind {
+		case types.String:
+			return []*types.Value{types.NewIntValue(int64(len(v.String)))}, nil
+		case types.Array:
+			return []*types.Value{types.NewIntValue(int64(len(v.Array)))}, nil
+		case types.Object:
+			return []*types.Value{types.NewIntValue(int64(len(v.Object)))}, nil
+		default:
+			return nil, fmt.Errorf("query: len doesn't support %v", v.Kind)
+		}
+	case "keys":
+		if len(c.args) != 1 {
+			return nil, fmt.Errorf("query: keys takes exactly one argument")
+		}
+		v, err := evalScalar(c.args[0], cur)
+		if err != nil {
+			return nil, err
+		}
+		if v.Kind != types.Object {
+			return nil, fmt.Errorf("query: keys requires an Object, got %v", v.Kind)
+		}
+		arr := make([]*types.Value, 0, len(v.Object))
+		for _, k := range sortedKeys(v.Object) {
+			arr = append(arr, types.NewStringValue([]byte(k)))
+		}
+		return []*types.Value{types.NewArrayValue(arr)}, nil
+	case "type":
+		if len(c.args) != 1 {
+			return nil, fmt.Errorf("query: type takes exactly one argument")
+		}
+		v, err := evalScalar(c.args[0], cur)
+		if err != nil {
+			return nil, err
+		}
+		return []*types.Value{types.NewStringValue([]byte(kindName(v.Kind)))}, nil
+	default:
+		return nil, fmt.Errorf("query: unknown function %q", c.name)
+	}
+}
+
+func kindName(k types.Kind) string {
+	switch k {
+	case types.Int:
+		return "int"
+	case types.Uint:
+		return "uint"
+	case types.Float:
+		return "float"
+	case types.String:
+		return "string"
+	case types.Bool:
+		return "bool"
+	case types.Array:
+		return "array"
+	case types.Object:
+		return "object"
+	default:
+		return "nil"
+	}
+}
@@ -0,0 +1,18 @@
+package query
+
+type literalKind int
+
+const (
+	literalNumber literalKind = iota
+	literalString
+	literalBool
+	literalNull
+)
+
+// literal is a compile-time constant that appears in a query expression.
+type literal struct {
+	kind literalKind
+	num  float64
+	str  string
+	b    bool
+}
@@ -0,0 +1,353 @@
+package query
+
+import "fmt"
+
+// parser turns a token stream into an expr tree using ordinary recursive
+// descent, with precedence, from loosest to tightest binding:
+// ||, &&, comparisons, + -, * /, unary -, and finally primaries
+// (literals, paths, function calls, and parenthesized expressions).
+type parser struct {
+	tz   *tokenizer
+	cur  token
+	errC error
+}
+
+func newParser(expr string) (*parser, error) {
+	p := &parser{tz: newTokenizer(expr)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *parser) advance() error {
+	tok, err := p.tz.next()
+	if err != nil {
+		return err
+	}
+	p.cur = tok
+	return nil
+}
+
+func (p *parser) expect(k tokenKind, what string) error {
+	if p.cur.kind != k {
+		return fmt.Errorf("query: expected %s", what)
+	}
+	return p.advance()
+}
+
+// parseProgram parses a full expression and ensures every token was consumed.
+func (p *parser) parseProgram() (expr, error) {
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur.kind != tokEOF {
+		return nil, fmt.Errorf("query: unexpected trailing input")
+	}
+	return e, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryExpr{op: "||", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	l, err := p.parseCmp()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.kind == tokAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseCmp()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryExpr{op: "&&", l: l, r: r}
+	}
+	return l, nil
+}
+
+var cmpOps = map[tokenKind]string{
+	tokEq:  "==",
+	tokNeq: "!=",
+	tokLt:  "<",
+	tokLe:  "<=",
+	tokGt:  ">",
+	tokGe:  ">=",
+}
+
+func (p *parser) parseCmp() (expr, error) {
+	l, err := p.parseAdd()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := cmpOps[p.cur.kind]; ok {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseAdd()
+		if err != nil {
+			return nil, err
+		}
+		return &binaryExpr{op: op, l: l, r: r}, nil
+	}
+	return l, nil
+}
+
+func (p *parser) parseAdd() (expr, error) {
+	l, err := p.parseMul()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.kind == tokPlus || p.cur.kind == tokMinus {
+		op := "+"
+		if p.cur.kind == tokMinus {
+			op = "-"
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseMul()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryExpr{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseMul() (expr, error) {
+	l, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.kind == tokStar || p.cur.kind == tokSlash {
+		op := "*"
+		if p.cur.kind == tokSlash {
+			op = "/"
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryExpr{op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.cur.kind == tokMinus {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryExpr{op: "-", e: e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	switch p.cur.kind {
+	case tokNumber:
+		n := p.cur.num
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: &literal{kind: literalNumber, num: n}}, nil
+	case tokString:
+		s := p.cur.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: &literal{kind: literalString, str: s}}, nil
+	case tokTrue:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: &literal{kind: literalBool, b: true}}, nil
+	case tokFalse:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: &literal{kind: literalBool, b: false}}, nil
+	case tokNull:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return &literalExpr{value: &literal{kind: literalNull}}, nil
+	case tokLParen:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokAt:
+		return p.parsePath(true)
+	case tokIdent:
+		name := p.cur.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.cur.kind == tokLParen {
+			return p.parseCall(name)
+		}
+		return p.parsePathFrom(fieldStep{name: name})
+	default:
+		return nil, fmt.Errorf("query: unexpected token in expression")
+	}
+}
+
+func (p *parser) parseCall(name string) (expr, error) {
+	if err := p.advance(); err != nil { // consume '('
+		return nil, err
+	}
+	var args []expr
+	if p.cur.kind != tokRParen {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if p.cur.kind != tokComma {
+				break
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := p.expect(tokRParen, "')'"); err != nil {
+		return nil, err
+	}
+	return &callExpr{name: name, args: args}, nil
+}
+
+// parsePath parses a path that starts with "@", e.g. "@.qty" or just "@".
+func (p *parser) parsePath(fromAt bool) (expr, error) {
+	if fromAt {
+		if err := p.advance(); err != nil { // consume '@'
+			return nil, err
+		}
+	}
+	return p.parsePathFrom()
+}
+
+// parsePathFrom parses the ".field" and "[...]" steps that follow either a
+// leading "@" or a bare identifier that was already consumed as the first
+// fieldStep.
+func (p *parser) parsePathFrom(initial ...fieldStep) (expr, error) {
+	steps := make([]step, 0, len(initial)+1)
+	for _, s := range initial {
+		s := s
+		steps = append(steps, &s)
+	}
+	for {
+		switch p.cur.kind {
+		case tokDot:
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.cur.kind != tokIdent {
+				return nil, fmt.Errorf("query: expected field name after '.'")
+			}
+			steps = append(steps, &fieldStep{name: p.cur.text})
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case tokLBracket:
+			s, err := p.parseBracketStep()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		default:
+			return &pathExpr{steps: steps}, nil
+		}
+	}
+}
+
+func (p *parser) parseBracketStep() (step, error) {
+	if err := p.advance(); err != nil { // consume '['
+		return nil, err
+	}
+	var s step
+	switch p.cur.kind {
+	case tokNumber:
+		s = &indexStep{index: int(p.cur.num)}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	case tokMinus:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.cur.kind != tokNumber {
+			return nil, fmt.Errorf("query: expected number after '-' inside '[...]'")
+		}
+		s = &indexStep{index: -int(p.cur.num)}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	case tokString:
+		s = &fieldStep{name: p.cur.text}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	case tokStar:
+		s = &wildcardStep{}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	case tokQuestion:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokLParen, "'('"); err != nil {
+			return nil, err
+		}
+		cond, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokRParen, "')'"); err != nil {
+			return nil, err
+		}
+		s = &filterStep{cond: cond}
+	default:
+		return nil, fmt.Errorf("query: unexpected token inside '[...]'")
+	}
+	if err := p.expect(tokRBracket, "']'"); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
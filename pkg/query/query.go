@@ -0,0 +1,38 @@
+// Package query compiles a small JSONPath-like expression language and
+// evaluates it against a *types.Value, for extracting or filtering data out
+// of decoded Watson without writing per-shape Go structs.
+//
+// The language supports dotted/bracketed path access (user.addresses[0].city),
+// wildcards (items[*].price), predicates (items[?(@.qty > 3 && @.name == "x")]),
+// and the built-ins len, keys, and type, plus arithmetic and comparisons.
+package query
+
+import "github.com/genkami/watson/pkg/types"
+
+// Program is a compiled query expression, ready to be run against any
+// number of *types.Values.
+type Program struct {
+	root expr
+}
+
+// Compile parses expr into a Program.
+func Compile(expr string) (*Program, error) {
+	p, err := newParser(expr)
+	if err != nil {
+		return nil, err
+	}
+	root, err := p.parseProgram()
+	if err != nil {
+		return nil, err
+	}
+	return &Program{root: root}, nil
+}
+
+// Run evaluates the Program against v and returns the resulting Values.
+// A path with no wildcards or predicates yields at most one Value; a path
+// containing a wildcard or a predicate may yield any number of them.
+// Scalar-returning programs, such as arithmetic expressions or comparisons,
+// always yield exactly one Value.
+func (p *Program) Run(v *types.Value) ([]*types.Value, error) {
+	return eval(p.root, v)
+}
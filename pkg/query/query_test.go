@@ -0,0 +1,141 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+func mustCompile(t *testing.T, expr string) *Program {
+	t.Helper()
+	p, err := Compile(expr)
+	if err != nil {
+		t.Fatalf("Compile(%q) failed: %s", expr, err)
+	}
+	return p
+}
+
+func sampleDoc() *types.Value {
+	return types.NewObjectValue(map[string]*types.Value{
+		"user": types.NewObjectValue(map[string]*types.Value{
+			"addresses": types.NewArrayValue([]*types.Value{
+				types.NewObjectValue(map[string]*types.Value{"city": types.NewStringValue([]byte("Tokyo"))}),
+				types.NewObjectValue(map[string]*types.Value{"city": types.NewStringValue([]byte("Osaka"))}),
+			}),
+		}),
+		"items": types.NewArrayValue([]*types.Value{
+			types.NewObjectValue(map[string]*types.Value{
+				"name": types.NewStringValue([]byte("x")),
+				"qty":  types.NewIntValue(5),
+				"price": types.NewIntValue(100),
+			}),
+			types.NewObjectValue(map[string]*types.Value{
+				"name": types.NewStringValue([]byte("y")),
+				"qty":  types.NewIntValue(1),
+				"price": types.NewIntValue(200),
+			}),
+		}),
+	})
+}
+
+func TestDottedAndBracketedPathAccess(t *testing.T) {
+	p := mustCompile(t, `user.addresses[0].city`)
+	got, err := p.Run(sampleDoc())
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	want := []*types.Value{types.NewStringValue([]byte("Tokyo"))}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNegativeIndex(t *testing.T) {
+	p := mustCompile(t, `items[-1].name`)
+	got, err := p.Run(sampleDoc())
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	want := []*types.Value{types.NewStringValue([]byte("y"))}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	p := mustCompile(t, `items[*].price`)
+	got, err := p.Run(sampleDoc())
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	want := []*types.Value{types.NewIntValue(100), types.NewIntValue(200)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestPredicate(t *testing.T) {
+	p := mustCompile(t, `items[?(@.qty > 3 && @.name == "x")]`)
+	got, err := p.Run(sampleDoc())
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(got))
+	}
+	if got[0].Object["name"].String == nil || string(got[0].Object["name"].String) != "x" {
+		t.Errorf("expected the item named \"x\", got %v", got[0])
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	cases := []struct {
+		expr string
+		want *types.Value
+	}{
+		{`len(items)`, types.NewIntValue(2)},
+		{`type(user)`, types.NewStringValue([]byte("object"))},
+	}
+	for _, c := range cases {
+		p := mustCompile(t, c.expr)
+		got, err := p.Run(sampleDoc())
+		if err != nil {
+			t.Fatalf("Run(%q) failed: %s", c.expr, err)
+		}
+		if diff := cmp.Diff([]*types.Value{c.want}, got); diff != "" {
+			t.Errorf("%q mismatch (-want +got):\n%s", c.expr, diff)
+		}
+	}
+}
+
+func TestNumericPromotionInComparisons(t *testing.T) {
+	doc := types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewIntValue(2),
+		"b": types.NewFloatValue(2.0),
+	})
+	p := mustCompile(t, `a == b`)
+	got, err := p.Run(doc)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if len(got) != 1 || got[0].Kind != types.Bool || !got[0].Bool {
+		t.Errorf("expected Int(2) == Float(2.0) to be true, got %v", got)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	doc := types.NewObjectValue(map[string]*types.Value{
+		"a": types.NewIntValue(2),
+		"b": types.NewIntValue(3),
+	})
+	p := mustCompile(t, `a + b`)
+	got, err := p.Run(doc)
+	if err != nil {
+		t.Fatalf("Run failed: %s", err)
+	}
+	if diff := cmp.Diff([]*types.Value{types.NewIntValue(5)}, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,255 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokTrue
+	tokFalse
+	tokNull
+	tokAt
+	tokDot
+	tokLBracket
+	tokRBracket
+	tokLParen
+	tokRParen
+	tokComma
+	tokStar
+	tokQuestion
+	tokAnd
+	tokOr
+	tokEq
+	tokNeq
+	tokLe
+	tokGe
+	tokLt
+	tokGt
+	tokPlus
+	tokMinus
+	tokSlash
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	num  float64
+}
+
+type tokenizer struct {
+	src []rune
+	pos int
+}
+
+func newTokenizer(expr string) *tokenizer {
+	return &tokenizer{src: []rune(expr)}
+}
+
+func (t *tokenizer) peekRune() (rune, bool) {
+	if t.pos >= len(t.src) {
+		return 0, false
+	}
+	return t.src[t.pos], true
+}
+
+func (t *tokenizer) skipSpace() {
+	for {
+		r, ok := t.peekRune()
+		if !ok || !unicode.IsSpace(r) {
+			return
+		}
+		t.pos++
+	}
+}
+
+// next scans and returns the next token in the expression.
+func (t *tokenizer) next() (token, error) {
+	t.skipSpace()
+	r, ok := t.peekRune()
+	if !ok {
+		return token{kind: tokEOF}, nil
+	}
+
+	switch r {
+	case '.':
+		t.pos++
+		return token{kind: tokDot}, nil
+	case '[':
+		t.pos++
+		return token{kind: tokLBracket}, nil
+	case ']':
+		t.pos++
+		return token{kind: tokRBracket}, nil
+	case '(':
+		t.pos++
+		return token{kind: tokLParen}, nil
+	case ')':
+		t.pos++
+		return token{kind: tokRParen}, nil
+	case ',':
+		t.pos++
+		return token{kind: tokComma}, nil
+	case '*':
+		t.pos++
+		return token{kind: tokStar}, nil
+	case '?':
+		t.pos++
+		return token{kind: tokQuestion}, nil
+	case '@':
+		t.pos++
+		return token{kind: tokAt}, nil
+	case '+':
+		t.pos++
+		return token{kind: tokPlus}, nil
+	case '-':
+		t.pos++
+		return token{kind: tokMinus}, nil
+	case '/':
+		t.pos++
+		return token{kind: tokSlash}, nil
+	case '=':
+		if t.lookaheadIs('=') {
+			t.pos += 2
+			return token{kind: tokEq}, nil
+		}
+		return token{}, fmt.Errorf("query: unexpected '=' at position %d", t.pos)
+	case '!':
+		if t.lookaheadIs('=') {
+			t.pos += 2
+			return token{kind: tokNeq}, nil
+		}
+		return token{}, fmt.Errorf("query: unexpected '!' at position %d", t.pos)
+	case '<':
+		if t.lookaheadIs('=') {
+			t.pos += 2
+			return token{kind: tokLe}, nil
+		}
+		t.pos++
+		return token{kind: tokLt}, nil
+	case '>':
+		if t.lookaheadIs('=') {
+			t.pos += 2
+			return token{kind: tokGe}, nil
+		}
+		t.pos++
+		return token{kind: tokGt}, nil
+	case '&':
+		if t.lookaheadIs('&') {
+			t.pos += 2
+			return token{kind: tokAnd}, nil
+		}
+		return token{}, fmt.Errorf("query: unexpected '&' at position %d", t.pos)
+	case '|':
+		if t.lookaheadIs('|') {
+			t.pos += 2
+			return token{kind: tokOr}, nil
+		}
+		return token{}, fmt.Errorf("query: unexpected '|' at position %d", t.pos)
+	case '"':
+		return t.scanString()
+	}
+
+	if unicode.IsDigit(r) {
+		return t.scanNumber()
+	}
+	if isIdentStart(r) {
+		return t.scanIdent()
+	}
+	return token{}, fmt.Errorf("query: unexpected character %q at position %d", r, t.pos)
+}
+
+func (t *tokenizer) lookaheadIs(r rune) bool {
+	return t.pos+1 < len(t.src) && t.src[t.pos+1] == r
+}
+
+func isIdentStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func isIdentPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func (t *tokenizer) scanIdent() (token, error) {
+	start := t.pos
+	for {
+		r, ok := t.peekRune()
+		if !ok || !isIdentPart(r) {
+			break
+		}
+		t.pos++
+	}
+	s := string(t.src[start:t.pos])
+	switch s {
+	case "true":
+		return token{kind: tokTrue}, nil
+	case "false":
+		return token{kind: tokFalse}, nil
+	case "null":
+		return token{kind: tokNull}, nil
+	default:
+		return token{kind: tokIdent, text: s}, nil
+	}
+}
+
+func (t *tokenizer) scanNumber() (token, error) {
+	start := t.pos
+	for {
+		r, ok := t.peekRune()
+		if !ok || !(unicode.IsDigit(r) || r == '.') {
+			break
+		}
+		t.pos++
+	}
+	s := string(t.src[start:t.pos])
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return token{}, fmt.Errorf("query: invalid number %q: %w", s, err)
+	}
+	return token{kind: tokNumber, num: n, text: s}, nil
+}
+
+func (t *tokenizer) scanString() (token, error) {
+	t.pos++ // opening quote
+	var b strings.Builder
+	for {
+		r, ok := t.peekRune()
+		if !ok {
+			return token{}, fmt.Errorf("query: unterminated string literal")
+		}
+		t.pos++
+		if r == '"' {
+			return token{kind: tokString, text: b.String()}, nil
+		}
+		if r == '\\' {
+			esc, ok := t.peekRune()
+			if !ok {
+				return token{}, fmt.Errorf("query: unterminated string literal")
+			}
+			t.pos++
+			switch esc {
+			case '"':
+				b.WriteRune('"')
+			case '\\':
+				b.WriteRune('\\')
+			case 'n':
+				b.WriteRune('\n')
+			case 't':
+				b.WriteRune('\t')
+			default:
+				b.WriteRune(esc)
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+}
@@ -0,0 +1,210 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrKindMismatch is returned by FromValue when a Value's Kind can not be
+// assigned to the Go type that is being decoded into.
+var ErrKindMismatch = errors.New("kind mismatch")
+
+// Unmarshaler is implemented by types that want to decode themselves from a *Value,
+// similarly to how Marshaler lets a type encode itself into one.
+type Unmarshaler interface {
+	UnmarshalWatson(v *Value) error
+}
+
+// FromValue decodes v into out, where out must be a non-nil pointer.
+// It does almost the exact opposite of ToValue.
+// If out implements Unmarshaler, FromValue calls out.UnmarshalWatson(v) instead of any of the rules below:
+//   * If out points to any of int, int8, int16, int32, or int64, v must be Int.
+//   * If out points to any of uint, uint8, uint16, uint32, or uint64, v must be Uint.
+//   * If out points to float32 or float64, v must be Float.
+//   * If out points to bool, v must be Bool.
+//   * If out points to string, v must be String.
+//   * If out points to a struct, v must be Object, and fields are populated by the same "watson" tag rules as ToValue.
+//   * If out points to a slice or an array, v must be Array, and elements are decoded by these rules.
+//   * If out points to a map, v must be Object, and entries are decoded by these rules.
+//   * If out points to a pointer, it is set to nil if v is Nil; otherwise a new value is allocated
+//     if the pointer is nil, and these rules are applied to the value it points to.
+//
+// FromValue returns ErrKindMismatch, wrapped with v's Kind and the target type, instead of panicking
+// when v's Kind can not be assigned to out's type.
+func FromValue(v *Value, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("types: FromValue: out must be a non-nil pointer, got %T", out)
+	}
+	return fromValueByReflection(v, rv.Elem())
+}
+
+func fromValueByReflection(v *Value, out reflect.Value) error {
+	if out.CanAddr() {
+		if u, ok := out.Addr().Interface().(Unmarshaler); ok {
+			return u.UnmarshalWatson(v)
+		}
+	}
+	switch {
+	case isIntFamily(out):
+		return intFromValue(v, out)
+	case isUintFamily(out):
+		return uintFromValue(v, out)
+	case isFloatFamily(out):
+		return floatFromValue(v, out)
+	case isBool(out):
+		return boolFromValue(v, out)
+	case isString(out):
+		return stringFromValue(v, out)
+	case out.Kind() == reflect.Ptr:
+		return ptrFromValue(v, out)
+	case isStruct(out):
+		return structFromValue(v, out)
+	case isArray(out):
+		return arrayFromValue(v, out)
+	case out.Kind() == reflect.Slice:
+		return sliceFromValue(v, out)
+	case out.Kind() == reflect.Map:
+		return mapFromValue(v, out)
+	}
+	return fmt.Errorf("types: FromValue: can't decode into %s", out.Type().String())
+}
+
+func kindMismatch(out reflect.Value, v *Value) error {
+	return fmt.Errorf("types: FromValue: can't assign %#v into %s: %w", v.Kind, out.Type().String(), ErrKindMismatch)
+}
+
+func intFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Int {
+		return kindMismatch(out, v)
+	}
+	out.SetInt(v.Int)
+	return nil
+}
+
+func uintFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Uint {
+		return kindMismatch(out, v)
+	}
+	out.SetUint(v.Uint)
+	return nil
+}
+
+func floatFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Float {
+		return kindMismatch(out, v)
+	}
+	out.SetFloat(v.Float)
+	return nil
+}
+
+func boolFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Bool {
+		return kindMismatch(out, v)
+	}
+	out.SetBool(v.Bool)
+	return nil
+}
+
+func stringFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != String {
+		return kindMismatch(out, v)
+	}
+	out.SetString(string(v.String))
+	return nil
+}
+
+func ptrFromValue(v *Value, out reflect.Value) error {
+	if v.Kind == Nil {
+		out.Set(reflect.Zero(out.Type()))
+		return nil
+	}
+	if out.IsNil() {
+		out.Set(reflect.New(out.Type().Elem()))
+	}
+	return fromValueByReflection(v, out.Elem())
+}
+
+func structFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Object {
+		return kindMismatch(out, v)
+	}
+	return setFields(v.Object, out)
+}
+
+func setFields(obj map[string]*Value, out reflect.Value) error {
+	t := out.Type()
+	size := out.NumField()
+	for i := 0; i < size; i++ {
+		field := t.Field(i)
+		tag := parseTag(&field)
+		if tag.ShouldAlwaysOmit() {
+			continue
+		}
+		elem := out.Field(i)
+		if tag.Inline() {
+			if err := setFields(obj, elem); err != nil {
+				return err
+			}
+			continue
+		}
+		fv, ok := obj[tag.Key()]
+		if !ok {
+			continue
+		}
+		if err := fromValueByReflection(fv, elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func arrayFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Array {
+		return kindMismatch(out, v)
+	}
+	if len(v.Array) != out.Len() {
+		return fmt.Errorf("types: FromValue: array length mismatch: expected %d, got %d", out.Len(), len(v.Array))
+	}
+	for i, elem := range v.Array {
+		if err := fromValueByReflection(elem, out.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sliceFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Array {
+		return kindMismatch(out, v)
+	}
+	slice := reflect.MakeSlice(out.Type(), len(v.Array), len(v.Array))
+	for i, elem := range v.Array {
+		if err := fromValueByReflection(elem, slice.Index(i)); err != nil {
+			return err
+		}
+	}
+	out.Set(slice)
+	return nil
+}
+
+func mapFromValue(v *Value, out reflect.Value) error {
+	if v.Kind != Object {
+		return kindMismatch(out, v)
+	}
+	if out.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("types: FromValue: map key type must be string, got %s", out.Type().Key().String())
+	}
+	m := reflect.MakeMapWithSize(out.Type(), len(v.Object))
+	elemType := out.Type().Elem()
+	for k, elem := range v.Object {
+		ev := reflect.New(elemType).Elem()
+		if err := fromValueByReflection(elem, ev); err != nil {
+			return err
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(out.Type().Key()), ev)
+	}
+	out.Set(m)
+	return nil
+}
@@ -0,0 +1,89 @@
+package types
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFromValueConvertsInt(t *testing.T) {
+	var got int64
+	err := FromValue(NewIntValue(123), &got)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != 123 {
+		t.Errorf("expected 123, got %d", got)
+	}
+}
+
+func TestFromValueConvertsString(t *testing.T) {
+	var got string
+	err := FromValue(NewStringValue([]byte("hey")), &got)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != "hey" {
+		t.Errorf("expected \"hey\", got %q", got)
+	}
+}
+
+func TestFromValueConvertsStruct(t *testing.T) {
+	type Person struct {
+		Name string `watson:"name"`
+		Age  int64  `watson:"age"`
+	}
+	val := NewObjectValue(map[string]*Value{
+		"name": NewStringValue([]byte("Taro")),
+		"age":  NewIntValue(25),
+	})
+	want := Person{Name: "Taro", Age: 25}
+	var got Person
+	if err := FromValue(val, &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFromValueConvertsSlice(t *testing.T) {
+	val := NewArrayValue([]*Value{NewIntValue(1), NewIntValue(2)})
+	want := []int64{1, 2}
+	var got []int64
+	if err := FromValue(val, &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFromValueReturnsErrKindMismatch(t *testing.T) {
+	var got int64
+	err := FromValue(NewStringValue([]byte("not an int")), &got)
+	if !errors.Is(err, ErrKindMismatch) {
+		t.Errorf("expected ErrKindMismatch, got %v", err)
+	}
+}
+
+type customUnmarshaler struct {
+	raw *Value
+}
+
+func (c *customUnmarshaler) UnmarshalWatson(v *Value) error {
+	c.raw = v
+	return nil
+}
+
+func TestFromValueCallsUnmarshaler(t *testing.T) {
+	val := NewIntValue(42)
+	var got customUnmarshaler
+	if err := FromValue(val, &got); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.raw != val {
+		t.Errorf("expected UnmarshalWatson to be called with %v, got %v", val, got.raw)
+	}
+}
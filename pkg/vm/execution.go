@@ -23,9 +23,25 @@ func (vm *VM) Top() (*types.Value, error) {
 	return vm.stack[vm.sp], nil
 }
 
+// Pop removes and returns the value in the top of the stack.
+// This returns ErrStackEmpty if the stack is empty.
+func (vm *VM) Pop() (*types.Value, error) {
+	return vm.pop()
+}
+
+// Depth returns the number of values currently held in the stack.
+func (vm *VM) Depth() int {
+	return vm.sp + 1
+}
+
 // Feed takes a op and executes corresponding operation.
-// This can fail in various ways; e.g. type mismatch, stack overflow, etc.
+// This can fail in various ways; e.g. type mismatch, stack overflow,
+// exceeding one of VMConfig's limits, etc.
 func (vm *VM) Feed(op Op) error {
+	if vm.config.MaxOps > 0 && vm.opsExecuted >= vm.config.MaxOps {
+		return ErrOpLimitExceeded
+	}
+	vm.opsExecuted++
 	switch op {
 	case Inew:
 		return vm.feedInew()
@@ -191,7 +207,14 @@ func (vm *VM) feedSadd() error {
 		return err
 	}
 	t := append(s, byte(n))
-	return vm.pushString(t)
+	if err := vm.checkStringLen(len(t)); err != nil {
+		return err
+	}
+	size := approxValueOverhead + len(t)
+	if err := vm.checkTotalBytes(size); err != nil {
+		return err
+	}
+	return vm.pushSized(types.NewStringValue(t), size)
 }
 
 func (vm *VM) feedOnew() error {
@@ -199,7 +222,7 @@ func (vm *VM) feedOnew() error {
 }
 
 func (vm *VM) feedOadd() error {
-	v, err := vm.pop()
+	v, vSize, err := vm.popSized()
 	if err != nil {
 		return err
 	}
@@ -207,12 +230,28 @@ func (vm *VM) feedOadd() error {
 	if err != nil {
 		return err
 	}
-	o, err := vm.popObject()
+	o, oldSize, err := vm.popObjectSized()
 	if err != nil {
 		return err
 	}
+	if err := vm.checkContainerLen(len(o) + 1); err != nil {
+		return err
+	}
+	// Adding vSize (rather than re-walking the whole object via approxSize)
+	// keeps this O(1) so that building an n-key object via n Oadds stays
+	// O(n) overall instead of O(n^2). A key already present in o is the one
+	// case that needs its old contribution subtracted back out first.
+	newSize := oldSize + vSize
+	if old, ok := o[string(k)]; ok {
+		newSize -= approxSize(old)
+	} else {
+		newSize += len(k)
+	}
 	o[string(k)] = v.DeepCopy()
-	return vm.pushObject(o)
+	if err := vm.checkTotalBytes(newSize); err != nil {
+		return err
+	}
+	return vm.pushSized(types.NewObjectValue(o), newSize)
 }
 
 func (vm *VM) feedAnew() error {
@@ -220,16 +259,25 @@ func (vm *VM) feedAnew() error {
 }
 
 func (vm *VM) feedAadd() error {
-	x, err := vm.pop()
+	x, xSize, err := vm.popSized()
 	if err != nil {
 		return err
 	}
-	a, err := vm.popArray()
+	a, oldSize, err := vm.popArraySized()
 	if err != nil {
 		return err
 	}
+	if err := vm.checkContainerLen(len(a) + 1); err != nil {
+		return err
+	}
 	a = append(a, x.DeepCopy())
-	return vm.pushArray(a)
+	// See feedOadd: adding xSize instead of re-walking a via approxSize
+	// keeps building an n-element array via n Aadds O(n) instead of O(n^2).
+	newSize := oldSize + xSize
+	if err := vm.checkTotalBytes(newSize); err != nil {
+		return err
+	}
+	return vm.pushSized(types.NewArrayValue(a), newSize)
 }
 
 func (vm *VM) feedBnew() error {
@@ -249,15 +297,20 @@ func (vm *VM) feedNnew() error {
 }
 
 func (vm *VM) feedGdup() error {
-	v, err := vm.pop()
+	v, size, err := vm.popSized()
 	if err != nil {
 		return err
 	}
-	err = vm.push(v)
-	if err != nil {
+	if err := vm.pushSized(v, size); err != nil {
+		return err
+	}
+	// v is back on the stack with its already-known size, so this checks
+	// the stack as it would look with the duplicate also pushed, exactly
+	// like feedSadd/feedOadd/feedAadd do, without re-walking v via approxSize.
+	if err := vm.checkTotalBytes(size); err != nil {
 		return err
 	}
-	return vm.push(v.DeepCopy())
+	return vm.pushSized(v.DeepCopy(), size)
 }
 
 func (vm *VM) feedGpop() error {
@@ -286,11 +339,22 @@ func (vm *VM) feedGswp() error {
 //
 
 func (vm *VM) push(v *types.Value) error {
+	return vm.pushSized(v, approxSize(v))
+}
+
+// pushSized pushes v onto the stack, recording size as its contribution to
+// approxBytes instead of deriving it from v via approxSize. Callers that
+// already know v's size (e.g. because it's a container built by adding a
+// single element to a previously-sized one) use this to avoid re-walking
+// the whole value on every push.
+func (vm *VM) pushSized(v *types.Value, size int) error {
 	if len(vm.stack)-1 <= vm.sp {
 		return ErrMaximumStackSizeExceeded
 	}
 	vm.sp++
 	vm.stack[vm.sp] = v
+	vm.sizes[vm.sp] = size
+	vm.approxBytes += size
 	return nil
 }
 
@@ -327,13 +391,25 @@ func (vm *VM) pushNil() error {
 }
 
 func (vm *VM) pop() (*types.Value, error) {
+	v, _, err := vm.popSized()
+	return v, err
+}
+
+// popSized is like pop, but also returns the size that was recorded for the
+// popped value when it was pushed, so callers that are about to push a
+// value derived from it (e.g. the same container plus one more element)
+// can compute the new size incrementally instead of via approxSize.
+func (vm *VM) popSized() (*types.Value, int, error) {
 	if vm.sp < 0 {
-		return nil, ErrStackEmpty
+		return nil, 0, ErrStackEmpty
 	}
 	top := vm.stack[vm.sp]
+	size := vm.sizes[vm.sp]
+	vm.approxBytes -= size
 	vm.stack[vm.sp] = nil
+	vm.sizes[vm.sp] = 0
 	vm.sp--
-	return top, nil
+	return top, size, nil
 }
 
 func (vm *VM) popInt() (int64, error) {
@@ -370,25 +446,35 @@ func (vm *VM) popString() ([]byte, error) {
 }
 
 func (vm *VM) popObject() (map[string]*types.Value, error) {
-	v, err := vm.pop()
+	o, _, err := vm.popObjectSized()
+	return o, err
+}
+
+func (vm *VM) popObjectSized() (map[string]*types.Value, int, error) {
+	v, size, err := vm.popSized()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if v.Kind != types.Object {
-		return nil, ErrTypeMismatch
+		return nil, 0, ErrTypeMismatch
 	}
-	return v.Object, nil
+	return v.Object, size, nil
 }
 
 func (vm *VM) popArray() ([]*types.Value, error) {
-	v, err := vm.pop()
+	a, _, err := vm.popArraySized()
+	return a, err
+}
+
+func (vm *VM) popArraySized() ([]*types.Value, int, error) {
+	v, size, err := vm.popSized()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if v.Kind != types.Array {
-		return nil, ErrTypeMismatch
+		return nil, 0, ErrTypeMismatch
 	}
-	return v.Array, nil
+	return v.Array, size, nil
 }
 
 func (vm *VM) popBool() (bool, error) {
@@ -0,0 +1,143 @@
+package vm
+
+import (
+	"errors"
+
+	"github.com/genkami/watson/pkg/types"
+)
+
+var (
+	// ErrOpLimitExceeded is returned by Feed once VMConfig.MaxOps ops have
+	// already been executed.
+	ErrOpLimitExceeded = errors.New("op limit exceeded")
+	// ErrValueTooLarge is returned by Feed when an op would grow a string or
+	// container past VMConfig.MaxStringLen / MaxContainerLen, or when Sadd,
+	// Oadd, Aadd or Gdup would grow the stack's total approximate size past
+	// VMConfig.MaxTotalBytes.
+	ErrValueTooLarge = errors.New("value too large")
+)
+
+// VMConfig bounds the resources a VM may consume while executing Watson
+// ops, which matters when those ops come from an untrusted source: a
+// hostile stream can otherwise allocate unbounded strings or containers
+// through nothing more than Sadd/Aadd loops.
+//
+// The zero VMConfig imposes no limits beyond the VM's fixed stack capacity.
+type VMConfig struct {
+	// MaxOps bounds the number of ops Feed will execute. Zero means unlimited.
+	MaxOps uint64
+	// MaxStringLen bounds the length in bytes of any single String value. Zero means unlimited.
+	MaxStringLen int
+	// MaxContainerLen bounds the number of elements of any single Array or Object value. Zero means unlimited.
+	MaxContainerLen int
+	// MaxTotalBytes bounds the VM's total approximate stack size, as reported by Stats. Zero means unlimited.
+	// It is checked only by Sadd, Oadd, Aadd and Gdup; ops that push a new scalar or an empty
+	// String, Array or Object are not checked against it and can take the total past it.
+	MaxTotalBytes int
+}
+
+// Stats reports how much work and memory a VM has used so far.
+type Stats struct {
+	// OpsExecuted is the number of ops Feed has successfully dispatched.
+	OpsExecuted uint64
+	// ApproxBytes is the current approximate heap size of the VM's stack contents.
+	ApproxBytes int
+}
+
+// Stats returns the VM's current execution statistics.
+func (vm *VM) Stats() Stats {
+	return Stats{OpsExecuted: vm.opsExecuted, ApproxBytes: vm.approxBytes}
+}
+
+// approxValueOverhead is a rough per-Value bookkeeping cost, so that even
+// small scalars count for something towards MaxTotalBytes.
+const approxValueOverhead = 16
+
+func approxSize(v *types.Value) int {
+	switch v.Kind {
+	case types.String:
+		return approxValueOverhead + len(v.String)
+	case types.Array:
+		size := approxValueOverhead
+		for _, elem := range v.Array {
+			size += approxSize(elem)
+		}
+		return size
+	case types.Object:
+		size := approxValueOverhead
+		for k, elem := range v.Object {
+			size += len(k) + approxSize(elem)
+		}
+		return size
+	default:
+		return approxValueOverhead
+	}
+}
+
+func (vm *VM) checkStringLen(n int) error {
+	if vm.config.MaxStringLen > 0 && n > vm.config.MaxStringLen {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+func (vm *VM) checkContainerLen(n int) error {
+	if vm.config.MaxContainerLen > 0 && n > vm.config.MaxContainerLen {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+// checkTotalBytes reports ErrValueTooLarge if the stack's current
+// approxBytes plus an additional value of the given size would exceed
+// VMConfig.MaxTotalBytes. Callers that are about to push (or replace a
+// popped value with) something of known size pass that size directly,
+// rather than handing over the whole value, so that checking the limit
+// never costs more than computing that size did.
+func (vm *VM) checkTotalBytes(size int) error {
+	if vm.config.MaxTotalBytes == 0 {
+		return nil
+	}
+	if vm.approxBytes+size > vm.config.MaxTotalBytes {
+		return ErrValueTooLarge
+	}
+	return nil
+}
+
+// Snapshot is a deep copy of a VM's stack and counters, taken by
+// VM.Snapshot and restored by VM.Restore.
+type Snapshot struct {
+	stack       []*types.Value
+	sizes       []int
+	sp          int
+	opsExecuted uint64
+	approxBytes int
+}
+
+// Snapshot deep-copies vm's current stack and counters so that a caller can
+// speculatively Feed further ops and roll back to this point with Restore if
+// one of them fails.
+func (vm *VM) Snapshot() *Snapshot {
+	stack := make([]*types.Value, vm.sp+1)
+	sizes := make([]int, vm.sp+1)
+	for i := 0; i <= vm.sp; i++ {
+		stack[i] = vm.stack[i].DeepCopy()
+		sizes[i] = vm.sizes[i]
+	}
+	return &Snapshot{stack: stack, sizes: sizes, sp: vm.sp, opsExecuted: vm.opsExecuted, approxBytes: vm.approxBytes}
+}
+
+// Restore resets vm's stack and counters to the state captured by s.
+func (vm *VM) Restore(s *Snapshot) {
+	for i := range vm.stack {
+		vm.stack[i] = nil
+		vm.sizes[i] = 0
+	}
+	for i, v := range s.stack {
+		vm.stack[i] = v.DeepCopy()
+		vm.sizes[i] = s.sizes[i]
+	}
+	vm.sp = s.sp
+	vm.opsExecuted = s.opsExecuted
+	vm.approxBytes = s.approxBytes
+}
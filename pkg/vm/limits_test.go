@@ -0,0 +1,120 @@
+package vm
+
+import "testing"
+
+func TestFeedReturnsErrOpLimitExceeded(t *testing.T) {
+	v := NewVM(VMConfig{MaxOps: 2})
+	if err := v.Feed(Inew); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Feed(Iinc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Feed(Iinc); err != ErrOpLimitExceeded {
+		t.Errorf("expected ErrOpLimitExceeded, got %v", err)
+	}
+}
+
+func TestFeedReturnsErrValueTooLargeForLongStrings(t *testing.T) {
+	v := NewVM(VMConfig{MaxStringLen: 2})
+	if err := v.FeedMulti([]Op{Snew, Inew, Sadd, Inew, Sadd}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Feed(Inew); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Feed(Sadd); err != ErrValueTooLarge {
+		t.Errorf("expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+func TestFeedReturnsErrValueTooLargeForBigContainers(t *testing.T) {
+	v := NewVM(VMConfig{MaxContainerLen: 1})
+	if err := v.FeedMulti([]Op{Anew, Inew, Aadd}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Feed(Inew); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := v.Feed(Aadd); err != ErrValueTooLarge {
+		t.Errorf("expected ErrValueTooLarge, got %v", err)
+	}
+}
+
+func TestFeedReturnsErrValueTooLargeForMaxTotalBytes(t *testing.T) {
+	v := NewVM(VMConfig{MaxTotalBytes: 30})
+	// Builds a 1-byte string, whose approximate size (17) fits under the limit.
+	if err := v.FeedMulti([]Op{Snew, Inew, Iinc, Sadd}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Duplicating that string would push the VM's approximate total past the
+	// 30-byte limit (two ~17-byte copies); Gdup must be checked just like
+	// Sadd/Oadd/Aadd are.
+	if err := v.Feed(Gdup); err != ErrValueTooLarge {
+		t.Errorf("expected ErrValueTooLarge from Gdup, got %v", err)
+	}
+}
+
+// TestStatsApproxBytesTracksIncrementalArrayGrowth guards against
+// checkTotalBytes/Stats going back to re-walking the whole array on every
+// Aadd: it builds a many-element array and checks that ApproxBytes matches
+// what a single top-to-bottom walk of the finished array would report,
+// without requiring such a walk during construction.
+func TestStatsApproxBytesTracksIncrementalArrayGrowth(t *testing.T) {
+	v := NewVM()
+	ops := []Op{Anew}
+	const n = 1000
+	for i := 0; i < n; i++ {
+		ops = append(ops, Inew, Aadd)
+	}
+	if err := v.FeedMulti(ops); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := (n + 1) * approxValueOverhead // the array itself, plus one Int per element
+	got := v.Stats().ApproxBytes
+	if got != want {
+		t.Errorf("expected ApproxBytes %d, got %d", want, got)
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	v := NewVM()
+	if err := v.FeedMulti([]Op{Inew, Iinc, Iinc}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	snap := v.Snapshot()
+
+	if err := v.Feed(Iinc); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	top, err := v.Top()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if top.Int != 3 {
+		t.Fatalf("expected 3, got %d", top.Int)
+	}
+
+	v.Restore(snap)
+	top, err = v.Top()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if top.Int != 2 {
+		t.Errorf("expected 2 after Restore, got %d", top.Int)
+	}
+}
+
+func TestStatsTracksOpsExecuted(t *testing.T) {
+	v := NewVM()
+	if err := v.FeedMulti([]Op{Inew, Iinc, Iinc}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	stats := v.Stats()
+	if stats.OpsExecuted != 3 {
+		t.Errorf("expected 3 ops executed, got %d", stats.OpsExecuted)
+	}
+	if stats.ApproxBytes <= 0 {
+		t.Errorf("expected a positive ApproxBytes, got %d", stats.ApproxBytes)
+	}
+}
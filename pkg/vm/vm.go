@@ -0,0 +1,39 @@
+package vm
+
+import "github.com/genkami/watson/pkg/types"
+
+// defaultStackSize is the fixed capacity of every VM's stack; VMConfig
+// does not change it.
+const defaultStackSize = 1 << 16
+
+// VM executes a sequence of Ops against a stack of *types.Values.
+type VM struct {
+	stack []*types.Value
+	// sizes[i] is the approxSize of stack[i] at the time it was pushed, kept
+	// alongside the stack so that approxBytes can be maintained incrementally
+	// instead of re-walking every container from scratch on each push/pop.
+	sizes []int
+	sp    int
+
+	config      VMConfig
+	opsExecuted uint64
+	// approxBytes is the running total of sizes[0..sp], i.e. what Stats
+	// reports as ApproxBytes.
+	approxBytes int
+}
+
+// NewVM returns a new VM with an empty stack.
+// An optional VMConfig bounds the resources the VM may consume; omitting it,
+// or passing the zero VMConfig, leaves the VM unbounded.
+func NewVM(config ...VMConfig) *VM {
+	var cfg VMConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	return &VM{
+		stack:  make([]*types.Value, defaultStackSize),
+		sizes:  make([]int, defaultStackSize),
+		sp:     -1,
+		config: cfg,
+	}
+}
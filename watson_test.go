@@ -0,0 +1,156 @@
+package watson
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	type Inner struct {
+		City string `watson:"city"`
+	}
+	type Person struct {
+		Name      string   `watson:"name"`
+		Age       int64    `watson:"age"`
+		Tags      []string `watson:"tags"`
+		Addresses []Inner  `watson:"addresses"`
+	}
+	want := Person{
+		Name: "Taro",
+		Age:  25,
+		Tags: []string{"a", "b"},
+		Addresses: []Inner{
+			{City: "Tokyo"},
+			{City: "Osaka"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(want); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	var got Person
+	if err := NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestStreamDecoderYieldsSuccessiveValues(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(int64(1)); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+	if err := enc.Encode("hey"); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+	if err := enc.Encode(map[string]interface{}{"a": int64(1)}); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	dec := NewStreamDecoder(&buf)
+
+	var i int64
+	if err := dec.Decode(&i); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if i != 1 {
+		t.Errorf("expected 1, got %d", i)
+	}
+
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if s != "hey" {
+		t.Errorf("expected \"hey\", got %q", s)
+	}
+
+	var m map[string]int64
+	if err := dec.Decode(&m); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if diff := cmp.Diff(map[string]int64{"a": 1}, m); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	var tail int64
+	if err := dec.Decode(&tail); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+// TestStreamDecoderRecordAfterObjectRecord guards against the Unlexer's Mode
+// leaking from one record into the next: an object-containing record flips
+// the Unlexer into mode S, and the following record must still be readable
+// by a fresh, mode-A Lexer.
+func TestStreamDecoderRecordAfterObjectRecord(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf)
+	if err := enc.Encode(map[string]interface{}{"a": int64(1)}); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+	if err := enc.Encode("hey"); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+
+	dec := NewStreamDecoder(&buf)
+
+	var m map[string]int64
+	if err := dec.Decode(&m); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if diff := cmp.Diff(map[string]int64{"a": 1}, m); diff != "" {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+
+	var s string
+	if err := dec.Decode(&s); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if s != "hey" {
+		t.Errorf("expected \"hey\", got %q", s)
+	}
+}
+
+func TestEncodeDecodeUint(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewEncoder(&buf).Encode(uint64(5)); err != nil {
+		t.Fatalf("Encode failed: %s", err)
+	}
+	var got uint64
+	if err := NewDecoder(&buf).Decode(&got); err != nil {
+		t.Fatalf("Decode failed: %s", err)
+	}
+	if got != 5 {
+		t.Errorf("expected 5, got %d", got)
+	}
+}
+
+// TestEncodeDecodeFloat exercises encodeFloat's path through encodeInt and
+// Itof, which reconstructs a float64's IEEE-754 bit pattern via Inew/Iinc/
+// Ishl/Ineg and relies on int64's two's-complement wraparound to get back
+// the exact bits, including when the sign bit is set.
+func TestEncodeDecodeFloat(t *testing.T) {
+	for _, want := range []float64{0, 1, -1, -3.14, 3.14, 1e300, 1e-300} {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(want); err != nil {
+			t.Fatalf("Encode(%v) failed: %s", want, err)
+		}
+		var got float64
+		if err := NewDecoder(&buf).Decode(&got); err != nil {
+			t.Fatalf("Decode failed: %s", err)
+		}
+		if got != want {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}